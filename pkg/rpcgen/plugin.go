@@ -0,0 +1,82 @@
+// Copyright 2012 Alec Thomas
+// Copyright (c) 2018 Samsung Electronics Co., Ltd All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcgen
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/alecthomas/template"
+)
+
+// Generator dispatches named plugins against a parsed RPCGen, writing each
+// plugin's rendered output to a caller-supplied io.Writer. A Generator
+// returned by NewGenerator has the built-in "core" (net/rpc and REST stubs)
+// and "cli" (companion CLI binary) plugins pre-registered; call
+// RegisterPlugin to add more, or to replace a built-in by name.
+type Generator struct {
+	plugins map[string]func(*RPCGen, io.Writer) error
+}
+
+// NewGenerator returns a Generator with the built-in "core" and "cli"
+// plugins registered.
+func NewGenerator() *Generator {
+	g := &Generator{plugins: map[string]func(*RPCGen, io.Writer) error{}}
+	g.RegisterPlugin("core", runCorePlugin)
+	g.RegisterPlugin("cli", runCLIPlugin)
+	return g
+}
+
+// RegisterPlugin adds (or replaces) the plugin registered under name. fn is
+// called with the parsed RPCGen and should write its generated source to w.
+func (g *Generator) RegisterPlugin(name string, fn func(*RPCGen, io.Writer) error) {
+	g.plugins[name] = fn
+}
+
+// Plugins returns the names of all currently registered plugins.
+func (g *Generator) Plugins() []string {
+	names := make([]string, 0, len(g.plugins))
+	for name := range g.plugins {
+		names = append(names, name)
+	}
+	return names
+}
+
+// Run executes the plugin registered under name, writing its generated
+// source to w.
+func (g *Generator) Run(name string, gen *RPCGen, w io.Writer) error {
+	fn, ok := g.plugins[name]
+	if !ok {
+		return fmt.Errorf("rpcgen: no plugin registered under %q", name)
+	}
+	return fn(gen, w)
+}
+
+func runCorePlugin(gen *RPCGen, w io.Writer) error {
+	t, err := template.New("rpc").Funcs(TemplateFuncs).Parse(CoreTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %s", err)
+	}
+	return t.Execute(w, gen)
+}
+
+func runCLIPlugin(gen *RPCGen, w io.Writer) error {
+	t, err := template.New("cli").Funcs(TemplateFuncs).Parse(CLITemplate)
+	if err != nil {
+		return fmt.Errorf("failed to parse CLI template: %s", err)
+	}
+	return t.Execute(w, gen)
+}
@@ -0,0 +1,443 @@
+// Copyright 2012 Alec Thomas
+// Copyright (c) 2018 Samsung Electronics Co., Ltd All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rpcgen parses a Go interface declaration into an RPCGen value that
+// the go-rpcgen templates render into RPC stubs. It is the stable surface
+// that out-of-tree plugins are written against; see Generator and
+// RegisterPlugin.
+package rpcgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Type is a single (possibly multi-name) parameter or result field, e.g.
+// the "a, b int" in "Add(a, b int) (result int, err error)".
+type Type struct {
+	Names      []string
+	LowerNames []string
+	Type       string
+}
+
+func (t *Type) NamesString() string {
+	return strings.Join(t.Names, ", ")
+}
+
+func (t *Type) LowerNamesString() string {
+	return strings.Join(t.LowerNames, ", ")
+}
+
+// Method describes one method of the parsed interface, plus the extra shape
+// information (streaming, context, HTTP routing, CLI flags) other templates
+// key off.
+type Method struct {
+	Name            string
+	Parameters      []*Type
+	Results         []*Type
+	HasContext      bool
+	IsStream        bool
+	StreamType      string
+	StreamFromSlice bool
+	HTTPVerb        string
+	HTTPPath        string
+	HTTPPathParams  []*HTTPPathParam
+	CLIParams       []*CLIParam
+}
+
+// CLIParam is a single flattened (name, type) parameter used to build one
+// --flag per method argument in the generated CLI.
+type CLIParam struct {
+	Name      string
+	FieldName string
+	Type      string
+}
+
+// HTTPPathParam is a {name} segment in a method's HTTP path that is bound
+// into a field of the method's request struct.
+type HTTPPathParam struct {
+	Name      string
+	FieldName string
+	Type      string
+}
+
+func FieldList(fields []*Type, prefix string, delim string, withTypes bool, public bool) string {
+	var out []string
+	for _, p := range fields {
+		suffix := ""
+		if withTypes {
+			suffix = " " + p.Type
+		}
+		names := p.LowerNames
+		if public {
+			names = p.Names
+		}
+		var field []string
+		for _, n := range names {
+			field = append(field, prefix+n)
+		}
+		out = append(out, strings.Join(field, ", ")+suffix)
+	}
+	return strings.Join(out, delim)
+}
+
+// KeyedFieldList renders fields as "Name: prefix+lowerName" pairs, for
+// building a keyed composite literal. Keyed literals don't require every
+// struct field to be listed, so this is safe to use even when the struct
+// has fields (e.g. Method.HasContext's deadline field) that fields doesn't
+// cover.
+func KeyedFieldList(fields []*Type, prefix string, delim string) string {
+	var out []string
+	for _, p := range fields {
+		for i, n := range p.Names {
+			out = append(out, fmt.Sprintf("%s: %s", n, prefix+p.LowerNames[i]))
+		}
+	}
+	return strings.Join(out, delim)
+}
+
+// RPCGen holds everything a template needs to render stubs for one
+// interface: the parsed methods plus the generation options that were in
+// effect while parsing (REST, CLI, transport backend).
+type RPCGen struct {
+	Service      string
+	Type         string
+	Package      string
+	Methods      []*Method
+	Imports      map[string]bool
+	RPCType      string
+	REST         bool
+	CLI          bool
+	CLIImport    string
+	Backend      string
+	fileset      *token.FileSet
+	CheckImports []*ast.ImportSpec
+}
+
+// HasStreamMethod reports whether any method of the interface is a streaming
+// method, so the template can decide whether to emit stream bookkeeping
+// fields on the generated service.
+func (r *RPCGen) HasStreamMethod() bool {
+	for _, m := range r.Methods {
+		if m.IsStream {
+			return true
+		}
+	}
+	return false
+}
+
+// HasContextMethod reports whether any method takes a context.Context first
+// argument, so the generated CLI knows whether to import "context".
+func (r *RPCGen) HasContextMethod() bool {
+	for _, m := range r.Methods {
+		if m.HasContext {
+			return true
+		}
+	}
+	return false
+}
+
+// HasNonStreamMethod reports whether any method of the interface isn't a
+// streaming method. The REST and CLI code paths are only emitted for
+// non-stream methods, so callers use this to decide whether the imports
+// those paths need are actually referenced.
+func (r *RPCGen) HasNonStreamMethod() bool {
+	for _, m := range r.Methods {
+		if !m.IsStream {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RPCGen) Visit(node ast.Node) (w ast.Visitor) {
+	switch n := node.(type) {
+	case *ast.ImportSpec:
+		r.CheckImports = append(r.CheckImports, n)
+
+	case *ast.TypeSpec:
+		name := n.Name.Name
+		if name == r.Type {
+			return &InterfaceGen{RPCGen: r}
+		}
+	}
+	return r
+}
+
+// ParseError is returned by Parse when the interface doesn't satisfy
+// go-rpcgen's constraints (e.g. a method missing a trailing error result).
+type ParseError struct {
+	Pos string
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// Parse parses source looking for an interface named typeName and populates
+// gen with its methods. gen's Service, REST and CLI fields must already be
+// set, since they affect what is parsed out of the interface.
+func Parse(source, typeName string, gen *RPCGen) (err error) {
+	fileset := token.NewFileSet()
+	f, err := parser.ParseFile(fileset, source, nil, parser.ParseComments)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %s", source, err)
+	}
+	gen.Type = typeName
+	gen.fileset = fileset
+	if gen.Package == "" {
+		gen.Package = f.Name.Name
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if pe, ok := r.(*ParseError); ok {
+				err = pe
+				return
+			}
+			panic(r)
+		}
+	}()
+	ast.Walk(gen, f)
+	return nil
+}
+
+type InterfaceGen struct {
+	*RPCGen
+}
+
+func (r *InterfaceGen) VisitMethodList(n *ast.InterfaceType) {
+	for _, m := range n.Methods.List {
+		switch t := m.Type.(type) {
+		case *ast.FuncType:
+			method := &Method{
+				Name:       m.Names[0].Name,
+				Parameters: make([]*Type, 0),
+				Results:    make([]*Type, 0),
+			}
+			for i, v := range t.Params.List {
+				if i == 0 && isContextType(v.Type) {
+					method.HasContext = true
+					r.Imports["context"] = true
+					r.Imports["time"] = true
+					continue
+				}
+				method.Parameters = append(method.Parameters, r.formatType(r.fileset, v))
+			}
+			hasError := false
+			if t.Results != nil {
+				for _, v := range t.Results.List {
+					result := r.formatType(r.fileset, v)
+					if result.Type == "error" {
+						hasError = true
+					} else {
+						method.Results = append(method.Results, result)
+					}
+				}
+			}
+			if !hasError {
+				fatalNode(r.fileset, m, "method %s must have error as last return value", method.Name)
+			}
+			if r.REST {
+				method.HTTPVerb, method.HTTPPath = httpRouteTag(m)
+				if method.HTTPVerb == "" {
+					method.HTTPVerb = "POST"
+				}
+				if method.HTTPPath == "" {
+					method.HTTPPath = fmt.Sprintf("/%s/%s", r.Service, method.Name)
+				}
+				for _, name := range pathVars(method.HTTPPath) {
+					param := &HTTPPathParam{Name: name, FieldName: strings.ToUpper(name[:1]) + name[1:], Type: "string"}
+					for _, p := range method.Parameters {
+						for i, lowerName := range p.LowerNames {
+							if lowerName == name {
+								param.FieldName = p.Names[i]
+								param.Type = p.Type
+							}
+						}
+					}
+					if param.Type == "int" {
+						r.Imports["strconv"] = true
+					}
+					method.HTTPPathParams = append(method.HTTPPathParams, param)
+				}
+			}
+			if n := len(method.Results); n > 0 {
+				last := method.Results[n-1]
+				isChan := strings.HasPrefix(last.Type, "<-chan ")
+				isSlice := strings.HasPrefix(last.Type, "[]")
+				if isChan || (hasStreamTag(m) && isSlice) {
+					method.IsStream = true
+					method.StreamFromSlice = isSlice
+					method.StreamType = strings.TrimPrefix(strings.TrimPrefix(last.Type, "<-chan "), "[]")
+					method.Results = method.Results[:n-1]
+					r.Imports["io"] = true
+					r.Imports["sync"] = true
+				}
+			}
+			if method.IsStream && method.HasContext {
+				fatalNode(r.fileset, m, "method %s must not take a context.Context first argument and return a stream: the background goroutine filling the stream outlives the call, so there is no single point to apply a deadline", method.Name)
+			}
+			if r.CLI && !method.IsStream {
+				for _, p := range method.Parameters {
+					for i, lowerName := range p.LowerNames {
+						method.CLIParams = append(method.CLIParams, &CLIParam{Name: lowerName, FieldName: p.Names[i], Type: p.Type})
+					}
+				}
+			}
+			r.Methods = append(r.Methods, method)
+		}
+	}
+	hasContext, hasPlain := false, false
+	for _, method := range r.Methods {
+		if method.HasContext {
+			hasContext = true
+		} else {
+			hasPlain = true
+		}
+	}
+	if hasContext && hasPlain {
+		fatalNode(r.fileset, n, "interface %s must not mix methods that take a context.Context first argument with methods that don't", r.Type)
+	}
+}
+
+// hasStreamTag reports whether the interface method field m carries a
+// "//rpcgen:stream" doc or trailing comment, used to opt a slice-returning
+// method into the streaming template even though it isn't channel-shaped.
+func hasStreamTag(m *ast.Field) bool {
+	for _, group := range []*ast.CommentGroup{m.Doc, m.Comment} {
+		if group == nil {
+			continue
+		}
+		for _, c := range group.List {
+			if strings.Contains(c.Text, "rpcgen:stream") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// httpRouteTag extracts the verb and path from a "//rpcgen:http GET /path/{id}"
+// doc or trailing comment on an interface method, returning "", "" if absent.
+func httpRouteTag(m *ast.Field) (verb, path string) {
+	for _, group := range []*ast.CommentGroup{m.Doc, m.Comment} {
+		if group == nil {
+			continue
+		}
+		for _, c := range group.List {
+			text := strings.TrimPrefix(strings.TrimPrefix(c.Text, "//"), " ")
+			if !strings.HasPrefix(text, "rpcgen:http ") {
+				continue
+			}
+			fields := strings.Fields(strings.TrimPrefix(text, "rpcgen:http "))
+			if len(fields) == 2 {
+				return fields[0], fields[1]
+			}
+		}
+	}
+	return "", ""
+}
+
+// pathVars returns the {name} segments of an HTTP path template, in order.
+func pathVars(path string) []string {
+	var names []string
+	for _, part := range strings.Split(path, "/") {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			names = append(names, part[1:len(part)-1])
+		}
+	}
+	return names
+}
+
+// isContextType reports whether expr is context.Context.
+func isContextType(expr ast.Expr) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	id, ok := sel.X.(*ast.Ident)
+	return ok && id.Name == "context" && sel.Sel.Name == "Context"
+}
+
+func (r *InterfaceGen) Visit(node ast.Node) (w ast.Visitor) {
+	switch n := node.(type) {
+	case *ast.InterfaceType:
+		r.VisitMethodList(n)
+	}
+	return r.RPCGen
+}
+
+func types(t ast.Expr) []string {
+	switch n := t.(type) {
+	case *ast.StarExpr:
+		return types(n.X)
+	case *ast.SelectorExpr:
+		return []string{strings.Join(append(types(n.X), types(n.Sel)...), ".")}
+	case *ast.MapType:
+		keys := types(n.Key)
+		return append(keys, types(n.Value)...)
+	case *ast.ArrayType:
+		return types(n.Elt)
+	case *ast.ChanType:
+		return types(n.Value)
+	case *ast.Ident:
+		return []string{n.Name}
+	default:
+		panic(fmt.Sprintf("unknown expression node %s %s\n", reflect.TypeOf(t), t))
+	}
+}
+
+func (r *InterfaceGen) formatType(fileset *token.FileSet, field *ast.Field) *Type {
+	var typeBuf bytes.Buffer
+	_ = printer.Fprint(&typeBuf, fileset, field.Type)
+	if len(field.Names) == 0 {
+		fatalNode(fileset, field, "RPC interface parameters and results must all be named")
+	}
+	for _, typeName := range types(field.Type) {
+		parts := strings.SplitN(typeName, ".", 2)
+		if len(parts) > 1 {
+			for _, imp := range r.CheckImports {
+				importPath := imp.Path.Value[1 : len(imp.Path.Value)-1]
+				if imp.Name != nil && imp.Name.String() == parts[0] {
+					r.Imports[fmt.Sprintf("%s %s", imp.Name, importPath)] = true
+				} else if filepath.Base(importPath) == parts[0] {
+					r.Imports[importPath] = true
+				}
+			}
+		}
+	}
+	t := &Type{Type: typeBuf.String()}
+	for _, n := range field.Names {
+		lowerName := n.Name
+		name := strings.ToUpper(lowerName[0:1]) + lowerName[1:]
+		t.Names = append(t.Names, name)
+		t.LowerNames = append(t.LowerNames, lowerName)
+	}
+	return t
+}
+
+// fatalNode aborts parsing of the current interface with a ParseError
+// carrying node's position. It is recovered by Parse.
+func fatalNode(fileset *token.FileSet, node ast.Node, format string, args ...interface{}) {
+	panic(&ParseError{Pos: fileset.Position(node.Pos()).String(), Msg: fmt.Sprintf(format, args...)})
+}
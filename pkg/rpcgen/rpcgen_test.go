@@ -0,0 +1,279 @@
+// Copyright 2012 Alec Thomas
+// Copyright (c) 2018 Samsung Electronics Co., Ltd All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcgen
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// genCase describes one interface shape to parse and generate stubs for,
+// and asserts the generated output actually compiles.
+type genCase struct {
+	name         string
+	source       string
+	rpcType      string
+	rest         bool
+	cli          bool
+	backend      string
+	streamOnly   bool // every method is a stream method; skips REST imports that only non-stream code uses
+	wantParseErr bool // Parse is expected to reject this shape; no generation is attempted
+}
+
+var genCases = []genCase{
+	{
+		name:    "plain",
+		rpcType: "Arith",
+		source: `package arith
+type Arith interface {
+	Add(a, b int) (result int, err error)
+}`,
+	},
+	{
+		name:    "context",
+		rpcType: "Arith",
+		source: `package arith
+import "context"
+type Arith interface {
+	Add(ctx context.Context, a, b int) (result int, err error)
+}`,
+	},
+	{
+		name:    "stream from channel",
+		rpcType: "Watcher",
+		source: `package arith
+type Watcher interface {
+	Watch(topic string) (events <-chan string, err error)
+}`,
+	},
+	{
+		name:    "stream from tagged slice",
+		rpcType: "Dumper",
+		source: `package arith
+type Dumper interface {
+	//rpcgen:stream
+	Dump(topic string) (events []string, err error)
+}`,
+	},
+	{
+		name:    "rest",
+		rpcType: "Arith",
+		rest:    true,
+		source: `package arith
+import "context"
+type Arith interface {
+	Add(ctx context.Context, a, b int) (result int, err error)
+}`,
+	},
+	{
+		name:    "cli",
+		rpcType: "Arith",
+		cli:     true,
+		source: `package arith
+type Arith interface {
+	Add(a, b int) (result int, err error)
+}`,
+	},
+	{
+		name:    "jsonrpc",
+		rpcType: "Arith",
+		backend: "jsonrpc",
+		source: `package arith
+type Arith interface {
+	Add(a, b int) (result int, err error)
+}`,
+	},
+	{
+		name:       "rest with stream-only interface",
+		rpcType:    "Watcher",
+		rest:       true,
+		streamOnly: true,
+		source: `package arith
+type Watcher interface {
+	Watch(topic string) (events <-chan string, err error)
+}`,
+	},
+	{
+		name:       "cli with stream-only interface",
+		rpcType:    "Watcher",
+		cli:        true,
+		streamOnly: true,
+		source: `package arith
+type Watcher interface {
+	Watch(topic string) (events <-chan string, err error)
+}`,
+	},
+	{
+		name:         "context and stream rejected",
+		rpcType:      "Watcher",
+		wantParseErr: true,
+		source: `package arith
+import "context"
+type Watcher interface {
+	Watch(ctx context.Context, topic string) (events <-chan string, err error)
+}`,
+	},
+}
+
+// TestGeneratedOutputBuilds parses each case's interface, runs it through
+// the real templates, and compiles the result. Generated code that merely
+// parses as valid Go isn't enough -- the struct-literal-arity and
+// ":=" -on-a-selector bugs that have shipped in this package were both
+// syntactically fine and only broke at the type-checking/build step.
+func TestGeneratedOutputBuilds(t *testing.T) {
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	for _, c := range genCases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			source := filepath.Join(dir, "arith.go")
+			if err := os.WriteFile(source, []byte(c.source), 0644); err != nil {
+				t.Fatalf("failed to write source: %s", err)
+			}
+
+			imports := map[string]bool{
+				"context": true,
+				"net/rpc": true,
+				"github.com/alecthomas/go-rpcgen/pkg/rpcgenrt": true,
+			}
+			if c.rest {
+				imports["net/http"] = true
+				imports["strings"] = true
+				if !c.streamOnly {
+					imports["encoding/json"] = true
+					imports["bytes"] = true
+					imports["fmt"] = true
+				}
+			}
+			if c.backend == "jsonrpc" {
+				imports["net"] = true
+				imports["net/rpc/jsonrpc"] = true
+				imports["io"] = true
+			}
+			backend := c.backend
+			if backend == "" {
+				backend = "netrpc"
+			}
+			gen := &RPCGen{
+				Service: c.rpcType,
+				RPCType: "*rpc.Client",
+				Package: "arith",
+				Imports: imports,
+				REST:    c.rest,
+				CLI:     c.cli,
+				Backend: backend,
+			}
+			if c.cli {
+				gen.CLIImport = "github.com/alecthomas/generated"
+			}
+			err := Parse(source, c.rpcType, gen)
+			if c.wantParseErr {
+				if err == nil {
+					t.Fatalf("Parse: expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %s", err)
+			}
+
+			generator := NewGenerator()
+			var buf bytes.Buffer
+			if err := generator.Run("core", gen, &buf); err != nil {
+				t.Fatalf("core plugin: %s", err)
+			}
+			writePackage(t, dir, "arith_rpc.go", buf.Bytes())
+
+			if c.cli {
+				var cliBuf bytes.Buffer
+				if err := generator.Run("cli", gen, &cliBuf); err != nil {
+					t.Fatalf("cli plugin: %s", err)
+				}
+				cliDir := filepath.Join(dir, "cli")
+				if err := os.MkdirAll(cliDir, 0755); err != nil {
+					t.Fatalf("failed to create cli dir: %s", err)
+				}
+				writePackage(t, cliDir, "main.go", cliBuf.Bytes())
+			}
+
+			buildGOPATHPackage(t, goBin, dir)
+		})
+	}
+}
+
+// writePackage writes generated source to name inside dir.
+func writePackage(t *testing.T, dir, name string, src []byte) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), src, 0644); err != nil {
+		t.Fatalf("failed to write %s: %s", name, err)
+	}
+}
+
+// buildGOPATHPackage builds the package at srcDir (plus the repo's
+// pkg/rpcgenrt runtime, which generated code always imports) inside a
+// throwaway GOPATH, so the test doesn't depend on network access or the
+// module the repo happens to be checked out under.
+func buildGOPATHPackage(t *testing.T, goBin, srcDir string) {
+	t.Helper()
+	gopath := t.TempDir()
+	pkgDir := filepath.Join(gopath, "src", "github.com", "alecthomas", "go-rpcgen")
+	if err := os.MkdirAll(filepath.Dir(pkgDir), 0755); err != nil {
+		t.Fatalf("failed to create GOPATH skeleton: %s", err)
+	}
+	if err := os.Rename(srcDir, filepath.Join(filepath.Dir(pkgDir), "generated")); err != nil {
+		t.Fatalf("failed to move generated source into GOPATH: %s", err)
+	}
+
+	rtSrc, err := filepath.Abs("../rpcgenrt")
+	if err != nil {
+		t.Fatalf("failed to resolve pkg/rpcgenrt: %s", err)
+	}
+	rtDst := filepath.Join(pkgDir, "pkg", "rpcgenrt")
+	if err := os.MkdirAll(rtDst, 0755); err != nil {
+		t.Fatalf("failed to create pkg/rpcgenrt dir: %s", err)
+	}
+	entries, err := os.ReadDir(rtSrc)
+	if err != nil {
+		t.Fatalf("failed to read pkg/rpcgenrt: %s", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		contents, err := os.ReadFile(filepath.Join(rtSrc, e.Name()))
+		if err != nil {
+			t.Fatalf("failed to read %s: %s", e.Name(), err)
+		}
+		if err := os.WriteFile(filepath.Join(rtDst, e.Name()), contents, 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", e.Name(), err)
+		}
+	}
+
+	cmd := exec.Command(goBin, "build", "./...")
+	cmd.Dir = filepath.Join(filepath.Dir(pkgDir), "generated")
+	cmd.Env = append(os.Environ(), "GOPATH="+gopath, "GO111MODULE=off", "GOFLAGS=")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("generated output does not build: %s\n%s", err, out)
+	}
+}
@@ -0,0 +1,427 @@
+// Copyright 2012 Alec Thomas
+// Copyright (c) 2018 Samsung Electronics Co., Ltd All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rpcgen
+
+// CoreTemplate renders the net/rpc (or jsonrpc) server and client stubs,
+// plus the optional REST handler/client when RPCGen.REST is set.
+var CoreTemplate = `// Generated by go-rpcgen. Do not modify.
+package {{.Package}}
+
+import (
+{{range $key, $value := .Imports}}  "{{$key}}"
+{{end}})
+{{$type := .Type}}
+type {{.Type}}Service struct {
+	impl         {{.Type}}
+	interceptors []rpcgenrt.Interceptor
+	{{if .HasStreamMethod}}streamsMu sync.Mutex
+	streams   map[uint64]chan interface{}
+	streamSeq uint64
+	{{end}}
+}
+
+func New{{.Type}}Service(impl {{.Type}}, opts ...rpcgenrt.Option) *{{.Type}}Service {
+	options := rpcgenrt.NewOptions(opts...)
+	return &{{.Type}}Service{
+		impl:         impl,
+		interceptors: options.Interceptors,
+		{{if .HasStreamMethod}}streams: map[uint64]chan interface{}{},
+		{{end}}
+	}
+}
+
+func Register{{.Type}}Service(server *rpc.Server, impl {{.Type}}, opts ...rpcgenrt.Option) error {
+	return server.RegisterName("{{.Service}}", New{{.Type}}Service(impl, opts...))
+}
+{{if eq .Backend "jsonrpc"}}
+// Serve{{.Type}}Conn serves a single JSON-RPC connection registered against
+// server. Call this from the Accept loop of a net.Listener.
+func Serve{{.Type}}Conn(server *rpc.Server, conn io.ReadWriteCloser) {
+	server.ServeCodec(jsonrpc.NewServerCodec(conn))
+}
+{{end}}
+{{range .Methods}}{{if .IsStream}}
+type {{$type}}{{.Name}}Request struct {
+	{{.Parameters | publicfields}}
+}
+
+type {{$type}}{{.Name}}Response struct {
+	StreamID uint64
+}
+
+type {{$type}}{{.Name}}NextRequest struct {
+	StreamID uint64
+}
+
+type {{$type}}{{.Name}}NextResponse struct {
+	Value {{.StreamType}}
+	EOF   bool
+}
+
+func (s *{{$type}}Service) {{.Name}}(request *{{$type}}{{.Name}}Request, response *{{$type}}{{.Name}}Response) error {
+	return rpcgenrt.Chain(context.Background(), "{{.Name}}", request, response, s.interceptors, func() error {
+		{{if .StreamFromSlice}}_items, err := s.impl.{{.Name}}({{.Parameters | publicrefswithprefix "request."}})
+		{{else}}_ch, err := s.impl.{{.Name}}({{.Parameters | publicrefswithprefix "request."}})
+		{{end}}if err != nil {
+			return err
+		}
+		s.streamsMu.Lock()
+		s.streamSeq++
+		response.StreamID = s.streamSeq
+		_out := make(chan interface{}, 16)
+		s.streams[response.StreamID] = _out
+		s.streamsMu.Unlock()
+		go func() {
+			{{if .StreamFromSlice}}for _, _v := range _items {
+				_out <- _v
+			}
+			{{else}}for _v := range _ch {
+				_out <- _v
+			}
+			{{end}}_out <- io.EOF
+		}()
+		return nil
+	})
+}
+
+func (s *{{$type}}Service) {{.Name}}Next(request *{{$type}}{{.Name}}NextRequest, response *{{$type}}{{.Name}}NextResponse) (err error) {
+	s.streamsMu.Lock()
+	_out, ok := s.streams[request.StreamID]
+	s.streamsMu.Unlock()
+	if !ok {
+		response.EOF = true
+		return
+	}
+	_v := <-_out
+	if _v == io.EOF {
+		s.streamsMu.Lock()
+		delete(s.streams, request.StreamID)
+		s.streamsMu.Unlock()
+		response.EOF = true
+		return
+	}
+	response.Value = _v.({{.StreamType}})
+	return
+}
+{{else}}
+type {{$type}}{{.Name}}Request struct {
+	{{.Parameters | publicfields}}
+	{{if .HasContext}}_XXXDeadlineUnixNano int64
+	{{end}}
+}
+
+type {{$type}}{{.Name}}Response struct {
+	{{.Results | publicfields}}
+}
+
+func (s *{{$type}}Service) {{.Name}}(request *{{$type}}{{.Name}}Request, response *{{$type}}{{.Name}}Response) error {
+	ctx := context.Background()
+	{{if .HasContext}}if request._XXXDeadlineUnixNano != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, time.Unix(0, request._XXXDeadlineUnixNano))
+		defer cancel()
+	}
+	{{end}}return rpcgenrt.Chain(ctx, "{{.Name}}", request, response, s.interceptors, func() error {
+		var err error
+		{{.Results | publicrefswithprefix "response."}}{{if .Results}}, {{end}}err = s.impl.{{.Name}}({{if .HasContext}}ctx{{if .Parameters}}, {{end}}{{end}}{{.Parameters | publicrefswithprefix "request."}})
+		return err
+	})
+}
+{{end}}{{end}}
+type {{.Type}}Client struct {
+	client       {{.RPCType}}
+	interceptors []rpcgenrt.Interceptor
+}
+
+func Dial{{.Type}}Client(addr string, opts ...rpcgenrt.Option) (*{{.Type}}Client, error) {
+	{{if eq .Backend "jsonrpc"}}conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return New{{.Type}}Client(jsonrpc.NewClient(conn), opts...), nil
+	{{else}}client, err := rpc.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return New{{.Type}}Client(client, opts...), nil
+	{{end}}
+}
+
+func New{{.Type}}Client(client {{.RPCType}}, opts ...rpcgenrt.Option) *{{.Type}}Client {
+	options := rpcgenrt.NewOptions(opts...)
+	return &{{.Type}}Client{client: client, interceptors: options.Interceptors}
+}
+
+func (_c *{{$type}}Client) Close() error {
+	return _c.client.Close()
+}
+{{range .Methods}}{{if .IsStream}}
+func (_c *{{$type}}Client) {{.Name}}({{.Parameters | functionargs}}) (<-chan {{.StreamType}}, error) {
+	_request := &{{$type}}{{.Name}}Request{{"{"}}{{.Parameters | keyedrefswithprefix ""}}{{"}"}}
+	_response := &{{$type}}{{.Name}}Response{}
+	_call := func() error { return _c.client.Call("{{$.Service}}.{{.Name}}", _request, _response) }
+	if err := rpcgenrt.Chain(context.Background(), "{{.Name}}", _request, _response, _c.interceptors, _call); err != nil {
+		return nil, err
+	}
+	_out := make(chan {{.StreamType}})
+	go func() {
+		defer close(_out)
+		for {
+			_nreq := &{{$type}}{{.Name}}NextRequest{StreamID: _response.StreamID}
+			_nresp := &{{$type}}{{.Name}}NextResponse{}
+			if err := _c.client.Call("{{$.Service}}.{{.Name}}Next", _nreq, _nresp); err != nil || _nresp.EOF {
+				return
+			}
+			_out <- _nresp.Value
+		}
+	}()
+	return _out, nil
+}
+{{else}}
+func (_c *{{$type}}Client) {{.Name}}({{if .HasContext}}ctx context.Context{{if .Parameters}}, {{end}}{{end}}{{.Parameters | functionargs}}) ({{.Results | functionargs}}{{if .Results}}, {{end}}err error) {
+	{{if not .HasContext}}ctx := context.Background()
+	{{end}}_request := &{{$type}}{{.Name}}Request{{"{"}}{{.Parameters | keyedrefswithprefix ""}}{{"}"}}
+	{{if .HasContext}}if deadline, ok := ctx.Deadline(); ok {
+		_request._XXXDeadlineUnixNano = deadline.UnixNano()
+	}
+	{{end}}_response := &{{$type}}{{.Name}}Response{}
+	err = rpcgenrt.Chain(ctx, "{{.Name}}", _request, _response, _c.interceptors, func() error {
+		{{if .HasContext}}_done := make(chan error, 1)
+		go func() {
+			_done <- _c.client.Call("{{$.Service}}.{{.Name}}", _request, _response)
+		}()
+		select {
+		case err := <-_done:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		{{else}}return _c.client.Call("{{$.Service}}.{{.Name}}", _request, _response)
+		{{end}}
+	})
+	return {{.Results | publicrefswithprefix "_response."}}{{if .Results}}, {{end}}err
+}
+{{end}}{{end}}{{if .REST}}
+func matchHTTPRoute(pattern, path string) (map[string]string, bool) {
+	patternParts := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathParts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternParts) != len(pathParts) {
+		return nil, false
+	}
+	vars := map[string]string{}
+	for i, part := range patternParts {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			vars[part[1:len(part)-1]] = pathParts[i]
+			continue
+		}
+		if part != pathParts[i] {
+			return nil, false
+		}
+	}
+	return vars, true
+}
+
+type {{.Type}}HTTPHandler struct {
+	impl         {{.Type}}
+	interceptors []rpcgenrt.Interceptor
+}
+
+func New{{.Type}}HTTPHandler(impl {{.Type}}, opts ...rpcgenrt.Option) *{{.Type}}HTTPHandler {
+	options := rpcgenrt.NewOptions(opts...)
+	return &{{.Type}}HTTPHandler{impl: impl, interceptors: options.Interceptors}
+}
+
+func (h *{{$type}}HTTPHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	{{range .Methods}}{{if not .IsStream}}if r.Method == "{{.HTTPVerb}}" {
+		if vars, ok := matchHTTPRoute("{{.HTTPPath}}", r.URL.Path); ok {
+			h.serve{{.Name}}(w, r, vars)
+			return
+		}
+	}
+	{{end}}{{end}}http.NotFound(w, r)
+}
+{{range .Methods}}{{if not .IsStream}}
+func (h *{{$type}}HTTPHandler) serve{{.Name}}(w http.ResponseWriter, r *http.Request, vars map[string]string) {
+	request := &{{$type}}{{.Name}}Request{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	{{range .HTTPPathParams}}if v, ok := vars["{{.Name}}"]; ok {
+		{{if eq .Type "int"}}if n, err := strconv.Atoi(v); err == nil {
+			request.{{.FieldName}} = n
+		}
+		{{else}}request.{{.FieldName}} = v
+		{{end}}
+	}
+	{{end}}response := &{{$type}}{{.Name}}Response{}
+	err := rpcgenrt.Chain(r.Context(), "{{.Name}}", request, response, h.interceptors, func() error {
+		var err error
+		{{.Results | publicrefswithprefix "response."}}{{if .Results}}, {{end}}err = h.impl.{{.Name}}({{if .HasContext}}r.Context(){{if .Parameters}}, {{end}}{{end}}{{.Parameters | publicrefswithprefix "request."}})
+		return err
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+{{end}}{{end}}
+type {{.Type}}HTTPClient struct {
+	BaseURL      string
+	Client       *http.Client
+	interceptors []rpcgenrt.Interceptor
+}
+
+func New{{.Type}}HTTPClient(baseURL string, opts ...rpcgenrt.Option) *{{.Type}}HTTPClient {
+	options := rpcgenrt.NewOptions(opts...)
+	return &{{.Type}}HTTPClient{BaseURL: baseURL, Client: http.DefaultClient, interceptors: options.Interceptors}
+}
+{{range .Methods}}{{if not .IsStream}}
+func (_c *{{$type}}HTTPClient) {{.Name}}({{if .HasContext}}ctx context.Context{{if .Parameters}}, {{end}}{{end}}{{.Parameters | functionargs}}) ({{.Results | functionargs}}{{if .Results}}, {{end}}err error) {
+	{{if not .HasContext}}ctx := context.Background()
+	{{end}}_request := &{{$type}}{{.Name}}Request{{"{"}}{{.Parameters | keyedrefswithprefix ""}}{{"}"}}
+	_response := &{{$type}}{{.Name}}Response{}
+	err = rpcgenrt.Chain(ctx, "{{.Name}}", _request, _response, _c.interceptors, func() error {
+		_body, err := json.Marshal(_request)
+		if err != nil {
+			return err
+		}
+		_url := _c.BaseURL + "{{.HTTPPath}}"
+		{{range .HTTPPathParams}}_url = strings.Replace(_url, "{"+"{{.Name}}"+"}", fmt.Sprint(_request.{{.FieldName}}), 1)
+		{{end}}_req, err := http.NewRequest("{{.HTTPVerb}}", _url, bytes.NewReader(_body))
+		if err != nil {
+			return err
+		}
+		_req = _req.WithContext(ctx)
+		_req.Header.Set("Content-Type", "application/json")
+		_resp, err := _c.Client.Do(_req)
+		if err != nil {
+			return err
+		}
+		defer _resp.Body.Close()
+		if _resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("{{.Name}}: unexpected status %s", _resp.Status)
+		}
+		return json.NewDecoder(_resp.Body).Decode(_response)
+	})
+	return {{.Results | publicrefswithprefix "_response."}}{{if .Results}}, {{end}}err
+}
+{{end}}{{end}}{{end}}`
+
+// CLITemplate renders a standalone main package that drives a generated
+// <Type>Client from one subcommand per interface method.
+var CLITemplate = `// Generated by go-rpcgen. Do not modify.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	{{if .HasNonStreamMethod}}"encoding/json"
+	{{end}}{{if .HasContextMethod}}"context"
+	{{end}}
+	pkg "{{.CLIImport}}"
+)
+
+type cliStringSlice []string
+
+func (s *cliStringSlice) String() string { return fmt.Sprint([]string(*s)) }
+func (s *cliStringSlice) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	fs := flag.NewFlagSet("{{.Type}}", flag.ContinueOnError)
+	addr := fs.String("addr", "", "address of the {{.Type}} RPC server")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	rest := fs.Args()
+	if len(rest) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: {{.Type}} --addr=<addr> <command> [flags]")
+		return 1
+	}
+	client, err := pkg.Dial{{.Type}}Client(*addr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer client.Close()
+	switch rest[0] {
+	{{range .Methods}}{{if not .IsStream}}case "{{.Name}}":
+		return run{{.Name}}(client, rest[1:])
+	{{end}}{{end}}default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", rest[0])
+		return 1
+	}
+}
+{{$type := .Type}}{{range .Methods}}{{if not .IsStream}}
+func run{{.Name}}(client *pkg.{{$type}}Client, args []string) int {
+	fs := flag.NewFlagSet("{{.Name}}", flag.ContinueOnError)
+	_json := fs.String("json", "", "parameters as a JSON object, overrides individual flags")
+	{{range .CLIParams}}{{if eq .Type "int"}}_{{.Name}} := fs.Int("{{.Name}}", 0, "")
+	{{else if eq .Type "bool"}}_{{.Name}} := fs.Bool("{{.Name}}", false, "")
+	{{else if eq .Type "[]string"}}_{{.Name}} := &cliStringSlice{}
+	fs.Var(_{{.Name}}, "{{.Name}}", "repeatable")
+	{{else}}_{{.Name}} := fs.String("{{.Name}}", "", "")
+	{{end}}{{end}}if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	_request := &pkg.{{$type}}{{.Name}}Request{}
+	if *_json != "" {
+		if err := json.Unmarshal([]byte(*_json), _request); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	} else {
+		{{range .CLIParams}}{{if eq .Type "[]string"}}_request.{{.FieldName}} = []string(*_{{.Name}})
+		{{else}}_request.{{.FieldName}} = *_{{.Name}}
+		{{end}}{{end}}
+	}
+	{{if .HasContext}}_ctx := context.Background()
+	{{end}}_resp := &pkg.{{$type}}{{.Name}}Response{}
+	var err error
+	{{.Results | publicrefswithprefix "_resp."}}{{if .Results}}, {{end}}err = client.{{.Name}}({{if .HasContext}}_ctx{{if .Parameters}}, {{end}}{{end}}{{.Parameters | publicrefswithprefix "_request."}})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	_out, _ := json.MarshalIndent(_resp, "", "  ")
+	fmt.Println(string(_out))
+	return 0
+}
+{{end}}{{end}}`
+
+// TemplateFuncs are the template.FuncMap entries CoreTemplate and
+// CLITemplate depend on. Plugins that parse their own templates against an
+// *RPCGen should include these too.
+var TemplateFuncs = map[string]interface{}{
+	"publicfields":         func(fields []*Type) string { return FieldList(fields, "", "\n\t", true, true) },
+	"refswithprefix":       func(prefix string, fields []*Type) string { return FieldList(fields, prefix, ", ", false, false) },
+	"keyedrefswithprefix":  func(prefix string, fields []*Type) string { return KeyedFieldList(fields, prefix, ", ") },
+	"publicrefswithprefix": func(prefix string, fields []*Type) string { return FieldList(fields, prefix, ", ", false, true) },
+	"functionargs":         func(fields []*Type) string { return FieldList(fields, "", ", ", true, false) },
+}
@@ -0,0 +1,69 @@
+// Copyright 2012 Alec Thomas
+// Copyright (c) 2018 Samsung Electronics Co., Ltd All Rights Reserved
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rpcgenrt is the runtime support library imported by code that
+// go-rpcgen generates. It has no dependency on pkg/rpcgen, so a generated
+// package can import it without pulling in the generator itself.
+package rpcgenrt
+
+import "context"
+
+// Interceptor wraps a single RPC call. method is the generated method name,
+// req and resp are the call's request/response values, and next invokes the
+// next interceptor in the chain, or the underlying server/client call for
+// the last interceptor. An interceptor that returns without calling next
+// short-circuits the call.
+type Interceptor func(ctx context.Context, method string, req, resp interface{}, next func() error) error
+
+// Options holds construction options accumulated from a chain of Option
+// functions, as passed to a generated <Type>Service or <Type>Client
+// constructor.
+type Options struct {
+	Interceptors []Interceptor
+}
+
+// Option configures a generated <Type>Service or <Type>Client.
+type Option func(*Options)
+
+// WithInterceptor appends interceptors to run around every call made through
+// a generated <Type>Service or <Type>Client, in the order given. Interceptors
+// registered by an earlier WithInterceptor call run outermost.
+func WithInterceptor(interceptors ...Interceptor) Option {
+	return func(o *Options) {
+		o.Interceptors = append(o.Interceptors, interceptors...)
+	}
+}
+
+// NewOptions applies opts and returns the resulting Options.
+func NewOptions(opts ...Option) Options {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Chain runs call through interceptors, outermost first, and returns its
+// error. It is invoked by generated server and client code around every RPC.
+func Chain(ctx context.Context, method string, req, resp interface{}, interceptors []Interceptor, call func() error) error {
+	next := call
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, n := interceptors[i], next
+		next = func() error {
+			return interceptor(ctx, method, req, resp, n)
+		}
+	}
+	return next()
+}